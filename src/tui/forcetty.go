@@ -0,0 +1,74 @@
+package tui
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// forceTTYEnvVar borrows the idea behind GitHub CLI's GH_FORCE_TTY: make
+// the renderer believe it has an interactive terminal of a given size even
+// when stderr isn't a real console, so CI can capture fzf's rendering
+// without a pty. SetForceTTY sets this same env var; no `--force-tty` flag
+// calls it yet in this tree.
+//
+// This parsing has no platform dependency, so it's shared by both
+// light_windows.go (Size()/initPlatform) and light_unix.go, which bypasses
+// its ioctl(TIOCGWINSZ) result the same way.
+const forceTTYEnvVar = "FZF_FORCE_TTY"
+
+// parseForceTTY accepts the same shapes as `gh`'s force-tty: "true" for an
+// 80x24 default, "COLSxLINES" for an explicit size, or "N%" to scale the
+// real console size fzf would otherwise have detected.
+func parseForceTTY(value string, real TermSize) (TermSize, bool) {
+	if value == "" {
+		return TermSize{}, false
+	}
+	if value == "true" || value == "1" {
+		return TermSize{Lines: 24, Columns: 80}, true
+	}
+	if strings.HasSuffix(value, "%") {
+		pct, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+		if err != nil || pct <= 0 {
+			return TermSize{}, false
+		}
+		return TermSize{
+			Lines:   real.Lines * pct / 100,
+			Columns: real.Columns * pct / 100,
+		}, true
+	}
+	cols, lines, ok := strings.Cut(value, "x")
+	if !ok {
+		return TermSize{}, false
+	}
+	c, err1 := strconv.Atoi(cols)
+	l, err2 := strconv.Atoi(lines)
+	if err1 != nil || err2 != nil || c <= 0 || l <= 0 {
+		return TermSize{}, false
+	}
+	return TermSize{Lines: l, Columns: c}, true
+}
+
+// forcedTTYSize reports the size set via FZF_FORCE_TTY, if any, given what
+// the real terminal would otherwise report (used to resolve percentages).
+func forcedTTYSize(real TermSize) (TermSize, bool) {
+	return parseForceTTY(os.Getenv(forceTTYEnvVar), real)
+}
+
+// forcedTTY reports whether FZF_FORCE_TTY is set at all, independent of
+// whether it parses to a usable size, since a set-but-malformed value
+// should still make the renderer treat stderr as interactive.
+func forcedTTY() bool {
+	return os.Getenv(forceTTYEnvVar) != ""
+}
+
+// SetForceTTY is the wiring point for a `--force-tty=COLSxLINES|true`
+// command-line flag: it accepts the same value shapes as FZF_FORCE_TTY and
+// sets the env var so initPlatform/Size pick it up exactly as if the user
+// had exported it, without the renderer having two separate code paths to
+// learn about. The options parser that registers the flag itself lives
+// outside this package and isn't present in this tree, so it isn't wired
+// up here; this only gives it something to call once it is.
+func SetForceTTY(value string) {
+	os.Setenv(forceTTYEnvVar, value)
+}
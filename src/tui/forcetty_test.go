@@ -0,0 +1,39 @@
+package tui
+
+import "testing"
+
+func TestParseForceTTYEmpty(t *testing.T) {
+	if _, ok := parseForceTTY("", TermSize{}); ok {
+		t.Error("empty value should not force a size")
+	}
+}
+
+func TestParseForceTTYTrue(t *testing.T) {
+	size, ok := parseForceTTY("true", TermSize{})
+	if !ok || size.Lines != 24 || size.Columns != 80 {
+		t.Errorf("got %+v, %v; want {Lines:24 Columns:80}, true", size, ok)
+	}
+}
+
+func TestParseForceTTYExplicitSize(t *testing.T) {
+	size, ok := parseForceTTY("120x40", TermSize{})
+	if !ok || size.Columns != 120 || size.Lines != 40 {
+		t.Errorf("got %+v, %v; want {Columns:120 Lines:40}, true", size, ok)
+	}
+}
+
+func TestParseForceTTYPercent(t *testing.T) {
+	real := TermSize{Lines: 40, Columns: 160}
+	size, ok := parseForceTTY("50%", real)
+	if !ok || size.Lines != 20 || size.Columns != 80 {
+		t.Errorf("got %+v, %v; want {Lines:20 Columns:80}, true", size, ok)
+	}
+}
+
+func TestParseForceTTYMalformed(t *testing.T) {
+	for _, v := range []string{"garbage", "0x0", "x40", "120x", "-5%"} {
+		if _, ok := parseForceTTY(v, TermSize{}); ok {
+			t.Errorf("%q should fail to parse", v)
+		}
+	}
+}
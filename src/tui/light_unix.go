@@ -0,0 +1,34 @@
+//go:build !windows
+
+package tui
+
+import "golang.org/x/sys/unix"
+
+// Size reports the terminal dimensions via ioctl(TIOCGWINSZ) on the tty fzf
+// opened for rendering, the same call the ncurses renderer relies on,
+// honoring FZF_FORCE_TTY the same way the Windows Console API path does so
+// CI can capture fzf's rendering without a real pty.
+func (r *LightRenderer) Size() TermSize {
+	var w, h, pxw, pxh int
+	if ws, err := unix.IoctlGetWinsize(int(r.ttyout.Fd()), unix.TIOCGWINSZ); err != nil {
+		w = getEnv("COLUMNS", defaultWidth)
+		h = r.maxHeightFunc(getEnv("LINES", defaultHeight))
+	} else {
+		w = int(ws.Col)
+		h = r.maxHeightFunc(int(ws.Row))
+		pxw = int(ws.Xpixel)
+		pxh = int(ws.Ypixel)
+	}
+	real := TermSize{h, w, pxw, pxh}
+	if forced, ok := forcedTTYSize(real); ok {
+		forced.Lines = r.maxHeightFunc(forced.Lines)
+		return forced
+	}
+	return real
+}
+
+func (r *LightRenderer) updateTerminalSize() {
+	size := r.Size()
+	r.width = size.Columns
+	r.height = size.Lines
+}
@@ -3,11 +3,11 @@
 package tui
 
 import (
+	"io"
 	"os"
 	"syscall"
 	"time"
 
-	"github.com/junegunn/fzf/src/util"
 	"golang.org/x/sys/windows"
 )
 
@@ -16,9 +16,18 @@ const (
 )
 
 var (
-	consoleFlagsInput  = uint32(windows.ENABLE_VIRTUAL_TERMINAL_INPUT | windows.ENABLE_PROCESSED_INPUT | windows.ENABLE_EXTENDED_FLAGS)
+	// ENABLE_EXTENDED_FLAGS without ENABLE_QUICK_EDIT_MODE disables the
+	// console's built-in quick-edit selection, which otherwise swallows
+	// mouse input before we ever see it. ENABLE_MOUSE_INPUT turns on
+	// MOUSE_EVENT_RECORDs so native mouse support doesn't depend on the
+	// terminal emulating xterm's SGR mouse sequences for us.
+	consoleFlagsInput  = uint32(windows.ENABLE_VIRTUAL_TERMINAL_INPUT | windows.ENABLE_PROCESSED_INPUT | windows.ENABLE_EXTENDED_FLAGS | windows.ENABLE_MOUSE_INPUT)
 	consoleFlagsOutput = uint32(windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING | windows.ENABLE_PROCESSED_OUTPUT | windows.DISABLE_NEWLINE_AUTO_RETURN)
 	counter            = uint64(0)
+
+	// pasteGapThreshold is the maximum inter-keystroke gap that still
+	// counts as part of the same bracketed-paste chunk.
+	pasteGapThreshold = 5 * time.Millisecond
 )
 
 // IsLightRendererSupported checks to see if the Light renderer is supported
@@ -42,38 +51,63 @@ func IsLightRendererSupported() bool {
 
 func (r *LightRenderer) DefaultTheme() *ColorTheme {
 	// the getenv check is borrowed from here: https://github.com/gdamore/tcell/commit/0c473b86d82f68226a142e96cc5a34c5a29b3690#diff-b008fcd5e6934bf31bc3d33bf49f47d8R178:
-	if !IsLightRendererSupported() || os.Getenv("ConEmuPID") != "" || os.Getenv("TCELL_TRUECOLOR") == "disable" {
+	if useAnsiEmulation() || !detectWindowsTerminal().SupportsTruecolor || os.Getenv("TCELL_TRUECOLOR") == "disable" {
 		return Default16
 	}
 	return Dark256
 }
 
 func (r *LightRenderer) initPlatform() error {
+	r.termProfile = detectWindowsTerminal()
+
 	//outHandle := windows.Stdout
 	outHandle, _ := syscall.Open("CONOUT$", syscall.O_RDWR, 0)
 	// enable vt100 emulation (https://docs.microsoft.com/en-us/windows/console/console-virtual-terminal-sequences)
 	if err := windows.GetConsoleMode(windows.Handle(outHandle), &r.origStateOutput); err != nil {
-		return err
+		// FZF_FORCE_TTY asks us to behave as if we're interactive even
+		// when there's no real console to query, e.g. piped into a CI
+		// log capture.
+		if !forcedTTY() {
+			return err
+		}
 	}
 	r.outHandle = uintptr(outHandle)
 	inHandle, _ := syscall.Open("CONIN$", syscall.O_RDWR, 0)
 	if err := windows.GetConsoleMode(windows.Handle(inHandle), &r.origStateInput); err != nil {
-		return err
+		if !forcedTTY() {
+			return err
+		}
 	}
 	r.inHandle = uintptr(inHandle)
 
 	// channel for non-blocking reads. Buffer to make sure
-	// we get the ESC sets:
+	// we get the ESC sets. Key, mouse, and focus events are all
+	// synthesized down to the VT bytes the existing key parser expects;
+	// resize instead drives updateTerminalSize() directly.
 	r.ttyinChannel = make(chan byte, 1024)
 
+	// Manual-reset stop event, signaled by restoreTerminal (pause, e.g.
+	// for 'execute') and closePlatform (final shutdown) alike, and reset
+	// by setupTerminal on every resume. WaitForMultipleObjects wakes the
+	// reader goroutine on either transition immediately, instead of
+	// leaving it blocked in ReadConsoleInputW racing the 'counter' bump
+	// until the next keystroke arrives.
+	stopEvent, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return err
+	}
+	r.stopEvent = stopEvent
+
 	r.setupTerminal()
 
 	return nil
 }
 
 func (r *LightRenderer) closePlatform() {
+	windows.SetEvent(r.stopEvent)
 	windows.SetConsoleMode(windows.Handle(r.outHandle), r.origStateOutput)
 	windows.SetConsoleMode(windows.Handle(r.inHandle), r.origStateInput)
+	windows.CloseHandle(r.stopEvent)
 }
 
 func openTtyIn(ttyDefault string) (*os.File, error) {
@@ -82,40 +116,187 @@ func openTtyIn(ttyDefault string) (*os.File, error) {
 }
 
 func openTtyOut(ttyDefault string) (*os.File, error) {
+	if useAnsiEmulation() {
+		// conhost here won't interpret the CSI/SGR bytes fzf writes, so
+		// splice a pipe in front of stderr and replay everything through
+		// the Console-API translator instead.
+		out, _ := syscall.Open("CONOUT$", syscall.O_RDWR, 0)
+		reader, writer, err := os.Pipe()
+		if err != nil {
+			return os.Stderr, nil
+		}
+		emulator := newAnsiConsoleWriter(windows.Handle(out))
+		go io.Copy(emulator, reader)
+		return writer, nil
+	}
 	return os.Stderr, nil
 }
 
 func (r *LightRenderer) setupTerminal() {
-	windows.SetConsoleMode(windows.Handle(r.outHandle), consoleFlagsOutput)
+	outputFlags := consoleFlagsOutput
+	if useAnsiEmulation() {
+		// ENABLE_VIRTUAL_TERMINAL_PROCESSING is exactly what this console
+		// doesn't support; setting it would just fail (or, worse, get
+		// silently ignored while CSI bytes pass straight through raw).
+		outputFlags &^= windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING
+	}
+	windows.SetConsoleMode(windows.Handle(r.outHandle), outputFlags)
 	windows.SetConsoleMode(windows.Handle(r.inHandle), consoleFlagsInput)
 
-	// The following allows for non-blocking IO.
-	// syscall.SetNonblock() is a NOOP under Windows.
+	// Clear any stop signal left over from a prior restoreTerminal
+	// (pause/resume for 'execute', or the initial 'suspend' toggle)
+	// before the new reader goroutine starts waiting on it.
+	windows.ResetEvent(r.stopEvent)
+
 	current := counter
 	go func() {
-		fd := int(r.inHandle)
-		b := make([]byte, 1)
+		inHandle := windows.Handle(r.inHandle)
+		waitHandles := []windows.Handle{r.stopEvent, inHandle}
 		for {
-			if _, err := util.Read(fd, b); err == nil {
-				r.mutex.Lock()
-				// This condition prevents the goroutine from running after the renderer
-				// has been closed or paused.
-				if current != counter {
-					r.mutex.Unlock()
-					break
-				}
-				r.ttyinChannel <- b[0]
-				// HACK: if run from PSReadline, something resets ConsoleMode to remove ENABLE_VIRTUAL_TERMINAL_INPUT.
-				windows.SetConsoleMode(windows.Handle(r.inHandle), consoleFlagsInput)
+			event, err := windows.WaitForMultipleObjects(waitHandles, false, windows.INFINITE)
+			if err != nil {
+				continue
+			}
+			// WaitForMultipleObjects returns the index of the signaled
+			// object relative to WAIT_OBJECT_0; index 0 is stopEvent.
+			if event == windows.WAIT_OBJECT_0 {
+				return
+			}
+
+			r.mutex.Lock()
+			// This condition prevents the goroutine from running after the renderer
+			// has been closed or paused.
+			if current != counter {
 				r.mutex.Unlock()
+				return
 			}
+			records, err := readConsoleInput(inHandle, 128)
+			if err == nil {
+				r.dispatchInputRecords(records)
+			}
+			// HACK: if run from PSReadline, something resets ConsoleMode to
+			// remove ENABLE_VIRTUAL_TERMINAL_INPUT. Terminals we can
+			// positively identify don't do this, so skip the
+			// re-assertion there rather than fight them every read.
+			if r.termProfile.Name == "" || r.termProfile.Name == "vt" || r.termProfile.Name == "conhost" {
+				windows.SetConsoleMode(inHandle, consoleFlagsInput)
+			}
+			r.mutex.Unlock()
 		}
 	}()
 }
 
+// dispatchInputRecords decodes the INPUT_RECORDs ReadConsoleInputW just
+// returned. Key events are coalesced into bracketed-paste chunks and/or
+// synthesized as VT bytes onto ttyinChannel; mouse and focus events are
+// translated into the same xterm SGR-mouse and focus escape sequences
+// fzf's key parser already understands, so none of that parsing needs to
+// change for Windows. Resize has no VT equivalent to synthesize, so it
+// goes straight to updateTerminalSize() - the same recompute the ncurses
+// renderer's resize handling ultimately triggers - instead of a second
+// signaling path nothing reads from.
+func (r *LightRenderer) dispatchInputRecords(records []inputRecord) {
+	for _, rec := range records {
+		switch rec.eventType {
+		case keyEventCode:
+			key := rec.asKeyEvent()
+			if key.keyDown == 0 {
+				continue
+			}
+			// A held key can arrive as a single record with
+			// wRepeatCount > 1 instead of N separate ones; replay it
+			// that many times so auto-repeat isn't silently dropped.
+			repeat := int(key.repeatCount)
+			if repeat < 1 {
+				repeat = 1
+			}
+			for i := 0; i < repeat; i++ {
+				r.feedKeyEvent(key)
+			}
+		case mouseEventCode:
+			// Terminals that already translate clicks into xterm SGR
+			// mouse sequences themselves (e.g. ConEmu with ANSI on) would
+			// otherwise have every click reported twice.
+			if r.termProfile.SupportsMouseSGR {
+				continue
+			}
+			r.flushPaste()
+			if seq := mouseEventToVT(rec.asMouseEvent(), &r.lastMouseButtons); seq != nil {
+				r.feedBytes(seq)
+			}
+		case windowBufferSizeEventCode:
+			r.updateTerminalSize()
+		case focusEventCode:
+			r.flushPaste()
+			r.feedBytes(focusEventToVT(rec.asFocusEvent()))
+		}
+	}
+}
+
+// feedKeyEvent groups rapidly-arriving printable keystrokes into a single
+// bracketed-paste chunk instead of feeding them through one at a time,
+// matching the behavior terminals with ENABLE_VIRTUAL_TERMINAL_INPUT
+// bracketed paste already give fzf on POSIX. Non-printable keys (arrows,
+// function keys, Enter, ...) flush and bypass the paste buffer entirely.
+func (r *LightRenderer) feedKeyEvent(key keyEventRecord) {
+	now := time.Now()
+	// unicodeChar is non-zero for the whole C0 control range too -
+	// Enter (\r), Tab, Escape, and Ctrl-letter combos (Ctrl-N/Ctrl-P,
+	// which fzf binds to down/up) all carry one. None of those are
+	// "printable" in the sense this buffer cares about, so they must
+	// bypass it the same as a bare virtualKeyCode-only key; otherwise a
+	// fast Enter right after typing a query gets merged into the
+	// preceding paste chunk instead of accepting it.
+	if key.unicodeChar == 0 || key.unicodeChar < 0x20 {
+		r.flushPaste()
+		r.feedBytes(keyEventToVT(key))
+		return
+	}
+	if len(r.pasteBuffer) > 0 &&
+		now.Sub(r.pasteLastTime) < pasteGapThreshold &&
+		key.controlKeyState == r.pasteControlState {
+		r.pasteBuffer = append(r.pasteBuffer, keyEventToVT(key)...)
+	} else {
+		r.flushPaste()
+		r.pasteBuffer = keyEventToVT(key)
+		r.pasteControlState = key.controlKeyState
+	}
+	r.pasteLastTime = now
+}
+
+// flushPaste emits whatever has been gathered in the paste buffer, wrapping
+// it in DECSET 2004 bracketed-paste markers when more than one keystroke
+// was coalesced; a single buffered character is just an ordinary keystroke.
+func (r *LightRenderer) flushPaste() {
+	if len(r.pasteBuffer) == 0 {
+		return
+	}
+	// Each keystroke contributes one rune, which may be several bytes.
+	if len([]rune(string(r.pasteBuffer))) > 1 {
+		r.feedBytes([]byte("\x1b[200~"))
+		r.feedBytes(r.pasteBuffer)
+		r.feedBytes([]byte("\x1b[201~"))
+	} else {
+		r.feedBytes(r.pasteBuffer)
+	}
+	r.pasteBuffer = nil
+}
+
+func (r *LightRenderer) feedBytes(b []byte) {
+	for _, c := range b {
+		r.ttyinChannel <- c
+	}
+}
+
 func (r *LightRenderer) restoreTerminal() {
 	r.mutex.Lock()
 	counter++
+	// Wake the reader goroutine deterministically instead of leaving it
+	// blocked in WaitForMultipleObjects on inHandle until the subprocess
+	// started by 'execute' happens to generate console input - until
+	// then it would otherwise still be eligible to steal input records
+	// meant for that subprocess.
+	windows.SetEvent(r.stopEvent)
 	// We're setting ENABLE_VIRTUAL_TERMINAL_INPUT to allow escape sequences to be read during 'execute'.
 	// e.g. fzf --bind 'enter:execute:less {}'
 	windows.SetConsoleMode(windows.Handle(r.inHandle), r.origStateInput|windows.ENABLE_VIRTUAL_TERMINAL_INPUT)
@@ -134,7 +315,12 @@ func (r *LightRenderer) Size() TermSize {
 		w = int(bufferInfo.Window.Right - bufferInfo.Window.Left)
 		h = r.maxHeightFunc(int(bufferInfo.Window.Bottom - bufferInfo.Window.Top))
 	}
-	return TermSize{h, w, 0, 0}
+	real := TermSize{h, w, 0, 0}
+	if forced, ok := forcedTTYSize(real); ok {
+		forced.Lines = r.maxHeightFunc(forced.Lines)
+		return forced
+	}
+	return real
 }
 
 func (r *LightRenderer) updateTerminalSize() {
@@ -156,8 +342,18 @@ func (r *LightRenderer) getch(nonblock bool) (int, bool) {
 		select {
 		case bc := <-r.ttyinChannel:
 			return int(bc), true
-		case <-time.After(timeoutInterval * time.Millisecond):
-			return 0, false
+		case <-waitableTimeout(timeoutInterval):
+			// Idle: nothing else is going to arrive to group with
+			// whatever's sitting in the paste buffer, so flush it now.
+			r.mutex.Lock()
+			r.flushPaste()
+			r.mutex.Unlock()
+			select {
+			case bc := <-r.ttyinChannel:
+				return int(bc), true
+			default:
+				return 0, false
+			}
 		}
 	} else {
 		bc := <-r.ttyinChannel
@@ -0,0 +1,407 @@
+//go:build windows
+
+package tui
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// ansiEmulationEnvVar forces the ANSI-emulation fallback renderer on even when
+// the console reports support for ENABLE_VIRTUAL_TERMINAL_PROCESSING, so the
+// code path can be exercised on VT-capable hosts during development.
+const ansiEmulationEnvVar = "FZF_WINDOWS_ANSI_EMULATION"
+
+// useAnsiEmulation reports whether the Light renderer should go through the
+// CSI/SGR-to-Console-API translation layer instead of relying on the
+// console's native VT100 support.
+func useAnsiEmulation() bool {
+	if os.Getenv(ansiEmulationEnvVar) != "" {
+		return true
+	}
+	return !IsLightRendererSupported()
+}
+
+// consolePalette16 are the RGB values conhost uses for the 16 legacy
+// console colors, indexed by the low 4 bits of a character attribute
+// (bit 0 blue, bit 1 green, bit 2 red, bit 3 intensity).
+var consolePalette16 = [16][3]float64{
+	{12, 12, 12}, {0, 55, 218}, {19, 161, 14}, {58, 150, 221},
+	{197, 15, 31}, {136, 23, 152}, {193, 156, 0}, {204, 204, 204},
+	{118, 118, 118}, {59, 120, 255}, {22, 198, 12}, {97, 214, 214},
+	{231, 72, 86}, {180, 0, 158}, {249, 241, 165}, {242, 242, 242},
+}
+
+// rgbToLab converts sRGB (0-255) to CIE-Lab for perceptual distance
+// comparisons when we need to collapse a 256-color or truecolor SGR
+// sequence down to the nearest of the console's 16 attribute colors.
+func rgbToLab(r, g, b float64) (l, a, bb float64) {
+	lin := func(c float64) float64 {
+		c /= 255
+		if c > 0.04045 {
+			return math.Pow((c+0.055)/1.055, 2.4)
+		}
+		return c / 12.92
+	}
+	rl, gl, bl := lin(r), lin(g), lin(b)
+	x := rl*0.4124 + gl*0.3576 + bl*0.1805
+	y := rl*0.2126 + gl*0.7152 + bl*0.0722
+	z := rl*0.0193 + gl*0.1192 + bl*0.9505
+
+	f := func(t float64) float64 {
+		if t > 0.008856 {
+			return math.Cbrt(t)
+		}
+		return 7.787*t + 16.0/116.0
+	}
+	fx, fy, fz := f(x/0.95047), f(y), f(z/1.08883)
+	return 116*fy - 16, 500 * (fx - fy), 200 * (fy - fz)
+}
+
+// nearestConsoleAttr maps a truecolor RGB triple to the closest of the 16
+// legacy console attribute colors by CIE-Lab distance, caching the result
+// since fzf tends to reuse a handful of colors across a render.
+var nearestConsoleAttrCache = map[uint32]uint16{}
+
+func nearestConsoleAttr(r, g, b byte) uint16 {
+	key := uint32(r)<<16 | uint32(g)<<8 | uint32(b)
+	if attr, ok := nearestConsoleAttrCache[key]; ok {
+		return attr
+	}
+	l1, a1, b1 := rgbToLab(float64(r), float64(g), float64(b))
+	best := uint16(0)
+	bestDist := math.MaxFloat64
+	for idx, rgb := range consolePalette16 {
+		l2, a2, b2 := rgbToLab(rgb[0], rgb[1], rgb[2])
+		dl, da, db := l1-l2, a1-a2, b1-b2
+		dist := dl*dl + da*da + db*db
+		if dist < bestDist {
+			bestDist = dist
+			best = uint16(idx)
+		}
+	}
+	nearestConsoleAttrCache[key] = best
+	return best
+}
+
+// ansiConsoleWriter parses the CSI/SGR stream fzf already emits and replays
+// it against the Console API, for hosts where conhost itself won't
+// interpret VT100 sequences (Windows 8 and older Server SKUs, some
+// MSYS/mingw shells). It implements io.Writer so it can be dropped in
+// wherever the renderer currently writes directly to the output handle.
+type ansiConsoleWriter struct {
+	handle windows.Handle
+	attr   uint16
+	// defaultAttr is the console's real starting attribute (PowerShell's
+	// blue background, a user's custom scheme, ...), captured once so
+	// SGR reset can restore it instead of assuming light-gray-on-black.
+	defaultAttr uint16
+	saved       windows.Coord
+	hasSave     bool
+	buf         []byte
+}
+
+func newAnsiConsoleWriter(handle windows.Handle) *ansiConsoleWriter {
+	var info windows.ConsoleScreenBufferInfo
+	windows.GetConsoleScreenBufferInfo(handle, &info)
+	return &ansiConsoleWriter{handle: handle, attr: info.Attributes, defaultAttr: info.Attributes}
+}
+
+func (w *ansiConsoleWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		esc := strings.IndexByte(string(w.buf), 0x1b)
+		if esc < 0 {
+			w.writeText(w.buf)
+			w.buf = nil
+			break
+		}
+		if esc > 0 {
+			w.writeText(w.buf[:esc])
+		}
+		rest := w.buf[esc:]
+		n := w.consumeEscape(rest)
+		if n == 0 {
+			// incomplete sequence, wait for more bytes
+			w.buf = rest
+			break
+		}
+		w.buf = rest[n:]
+	}
+	return len(p), nil
+}
+
+func (w *ansiConsoleWriter) writeText(b []byte) {
+	if len(b) == 0 {
+		return
+	}
+	var written uint32
+	windows.WriteConsole(w.handle, append([]uint16(nil), utf16Encode(string(b))...), &written, nil)
+}
+
+// consumeEscape parses a single CSI/SGR/DEC sequence at the start of b and
+// returns the number of bytes consumed, or 0 if b doesn't yet contain a
+// complete sequence.
+func (w *ansiConsoleWriter) consumeEscape(b []byte) int {
+	if len(b) < 2 {
+		return 0
+	}
+	switch b[1] {
+	case '[':
+		return w.consumeCSI(b)
+	case '7': // DECSC
+		w.saveCursor()
+		return 2
+	case '8': // DECRC
+		w.restoreCursor()
+		return 2
+	default:
+		return 2
+	}
+}
+
+func (w *ansiConsoleWriter) consumeCSI(b []byte) int {
+	i := 2
+	for i < len(b) && (b[i] < 0x40 || b[i] > 0x7e) {
+		i++
+	}
+	if i >= len(b) {
+		return 0
+	}
+	final := b[i]
+	params := strings.Split(string(b[2:i]), ";")
+	private := len(params) > 0 && strings.HasPrefix(params[0], "?")
+	if private {
+		params[0] = params[0][1:]
+	}
+	nums := make([]int, 0, len(params))
+	for _, p := range params {
+		if p == "" {
+			nums = append(nums, 0)
+			continue
+		}
+		n, _ := strconv.Atoi(p)
+		nums = append(nums, n)
+	}
+	switch {
+	case private && final == 'h', private && final == 'l':
+		w.privateMode(nums, final == 'h')
+	case final == 'm':
+		w.sgr(nums)
+	case final == 'H' || final == 'f':
+		w.cup(nums)
+	case final == 'A':
+		w.cursorMove(0, -arg(nums, 1))
+	case final == 'B':
+		w.cursorMove(0, arg(nums, 1))
+	case final == 'C':
+		w.cursorMove(arg(nums, 1), 0)
+	case final == 'D':
+		w.cursorMove(-arg(nums, 1), 0)
+	case final == 'J':
+		w.eraseDisplay(arg(nums, 0))
+	case final == 'K':
+		w.eraseLine(arg(nums, 0))
+	}
+	return i + 1
+}
+
+func arg(nums []int, def int) int {
+	if len(nums) == 0 || nums[0] == 0 {
+		return def
+	}
+	return nums[0]
+}
+
+func (w *ansiConsoleWriter) privateMode(nums []int, enable bool) {
+	for _, n := range nums {
+		switch n {
+		case 25: // cursor visibility
+			var info windows.ConsoleCursorInfo
+			windows.GetConsoleCursorInfo(w.handle, &info)
+			info.Visible = enable
+			windows.SetConsoleCursorInfo(w.handle, &info)
+		case 1049: // alt screen buffer
+			// Best effort: conhost has no alternate buffer concept; clear instead.
+			if enable {
+				w.eraseDisplay(2)
+			}
+		}
+	}
+}
+
+func (w *ansiConsoleWriter) sgr(nums []int) {
+	if len(nums) == 0 {
+		nums = []int{0}
+	}
+	// SGR numbers red 1 and blue 4; console attributes have those two
+	// bits swapped (bit 0 blue, bit 2 red).
+	toAttr := func(c int) uint16 { return uint16(c&^5 | (c&1)<<2 | (c&4)>>2) }
+	for i := 0; i < len(nums); i++ {
+		switch n := nums[i]; {
+		case n == 0:
+			// Restore the console's real starting attribute, not an
+			// assumed light-gray-on-black - the user may be running a
+			// custom color scheme or a dark-on-light one.
+			w.attr = w.defaultAttr
+		case n >= 30 && n <= 37:
+			w.setForeground(toAttr(n - 30))
+		case n >= 90 && n <= 97:
+			w.setForeground(toAttr(n-90) | 0x8)
+		case n >= 40 && n <= 47:
+			w.setBackground(toAttr(n - 40))
+		case n >= 100 && n <= 107:
+			w.setBackground(toAttr(n-100) | 0x8)
+		case n == 38 || n == 48:
+			i = w.sgrExtended(nums, i, n == 38)
+		}
+	}
+	windows.SetConsoleTextAttribute(w.handle, w.attr)
+}
+
+// sgrExtended handles the 256-color (`38;5;N`) and truecolor (`38;2;R;G;B`)
+// forms, collapsing both down to the nearest of the 16 legacy attributes.
+func (w *ansiConsoleWriter) sgrExtended(nums []int, i int, foreground bool) int {
+	if i+1 >= len(nums) {
+		return i
+	}
+	switch nums[i+1] {
+	case 5:
+		if i+2 >= len(nums) {
+			return i + 1
+		}
+		r, g, b := ansi256ToRGB(nums[i+2])
+		attr := nearestConsoleAttr(r, g, b)
+		if foreground {
+			w.setForeground(attr)
+		} else {
+			w.setBackground(attr)
+		}
+		return i + 2
+	case 2:
+		if i+4 >= len(nums) {
+			return i + 1
+		}
+		attr := nearestConsoleAttr(byte(nums[i+2]), byte(nums[i+3]), byte(nums[i+4]))
+		if foreground {
+			w.setForeground(attr)
+		} else {
+			w.setBackground(attr)
+		}
+		return i + 4
+	}
+	return i + 1
+}
+
+func (w *ansiConsoleWriter) setForeground(attr uint16) {
+	w.attr = (w.attr &^ 0x000f) | attr
+}
+
+func (w *ansiConsoleWriter) setBackground(attr uint16) {
+	w.attr = (w.attr &^ 0x00f0) | (attr << 4)
+}
+
+func (w *ansiConsoleWriter) cup(nums []int) {
+	row, col := arg(nums, 1), 1
+	if len(nums) > 1 {
+		col = nums[1]
+		if col == 0 {
+			col = 1
+		}
+	}
+	windows.SetConsoleCursorPosition(w.handle, windows.Coord{X: int16(col - 1), Y: int16(row - 1)})
+}
+
+func (w *ansiConsoleWriter) cursorMove(dx, dy int) {
+	var info windows.ConsoleScreenBufferInfo
+	if windows.GetConsoleScreenBufferInfo(w.handle, &info) != nil {
+		return
+	}
+	pos := info.CursorPosition
+	windows.SetConsoleCursorPosition(w.handle, windows.Coord{
+		X: pos.X + int16(dx),
+		Y: pos.Y + int16(dy),
+	})
+}
+
+func (w *ansiConsoleWriter) saveCursor() {
+	var info windows.ConsoleScreenBufferInfo
+	if windows.GetConsoleScreenBufferInfo(w.handle, &info) == nil {
+		w.saved = info.CursorPosition
+		w.hasSave = true
+	}
+}
+
+func (w *ansiConsoleWriter) restoreCursor() {
+	if w.hasSave {
+		windows.SetConsoleCursorPosition(w.handle, w.saved)
+	}
+}
+
+func (w *ansiConsoleWriter) eraseLine(mode int) {
+	var info windows.ConsoleScreenBufferInfo
+	if windows.GetConsoleScreenBufferInfo(w.handle, &info) != nil {
+		return
+	}
+	pos := info.CursorPosition
+	width := int(info.Size.X)
+	start, length := 0, width
+	switch mode {
+	case 0:
+		start, length = int(pos.X), width-int(pos.X)
+	case 1:
+		start, length = 0, int(pos.X)+1
+	}
+	var written uint32
+	origin := windows.Coord{X: int16(start), Y: pos.Y}
+	windows.FillConsoleOutputCharacter(w.handle, ' ', uint32(length), origin, &written)
+	windows.FillConsoleOutputAttribute(w.handle, w.attr, uint32(length), origin, &written)
+}
+
+func (w *ansiConsoleWriter) eraseDisplay(mode int) {
+	var info windows.ConsoleScreenBufferInfo
+	if windows.GetConsoleScreenBufferInfo(w.handle, &info) != nil {
+		return
+	}
+	var written uint32
+	size := uint32(info.Size.X) * uint32(info.Size.Y)
+	origin := windows.Coord{X: 0, Y: 0}
+	if mode != 2 {
+		// Only full-screen clear (ED2) is needed by fzf today.
+		origin = info.CursorPosition
+		size = uint32(info.Size.X)*uint32(info.Size.Y) - uint32(info.CursorPosition.Y)*uint32(info.Size.X) - uint32(info.CursorPosition.X)
+	}
+	windows.FillConsoleOutputCharacter(w.handle, ' ', size, origin, &written)
+	windows.FillConsoleOutputAttribute(w.handle, w.attr, size, origin, &written)
+}
+
+// ansi256ToRGB expands an xterm 256-color index to RGB, matching the table
+// fzf's own tui.PaletteColor already uses for non-Windows terminals.
+func ansi256ToRGB(n int) (byte, byte, byte) {
+	if n < 16 {
+		// xterm numbers red 1 and blue 4; console attributes have those
+		// two bits swapped, so swap them back before indexing the table.
+		rgb := consolePalette16[n&^5|(n&1)<<2|(n&4)>>2]
+		return byte(rgb[0]), byte(rgb[1]), byte(rgb[2])
+	}
+	if n < 232 {
+		n -= 16
+		levels := [6]byte{0, 95, 135, 175, 215, 255}
+		return levels[n/36], levels[(n/6)%6], levels[n%6]
+	}
+	gray := byte(8 + (n-232)*10)
+	return gray, gray, gray
+}
+
+func utf16Encode(s string) []uint16 {
+	// StringToUTF16 appends exactly one trailing NUL; trim that off
+	// rather than len([]rune(s)), which undercounts whenever s contains
+	// a supplementary-plane code point encoded as a surrogate pair and
+	// would truncate the tail of the string.
+	u := windows.StringToUTF16(s)
+	return u[:len(u)-1]
+}
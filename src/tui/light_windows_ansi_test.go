@@ -0,0 +1,54 @@
+//go:build windows
+
+package tui
+
+import "testing"
+
+func TestRgbToLabBlackAndWhite(t *testing.T) {
+	l, a, b := rgbToLab(0, 0, 0)
+	if l != 0 || a != 0 || b != 0 {
+		t.Errorf("black should map to L=0 a=0 b=0, got %v %v %v", l, a, b)
+	}
+	l, _, _ = rgbToLab(255, 255, 255)
+	if l < 99 || l > 101 {
+		t.Errorf("white should map to L~100, got %v", l)
+	}
+}
+
+func TestNearestConsoleAttrExactMatches(t *testing.T) {
+	for idx, rgb := range consolePalette16 {
+		got := nearestConsoleAttr(byte(rgb[0]), byte(rgb[1]), byte(rgb[2]))
+		if int(got) != idx {
+			t.Errorf("exact palette color %d mapped to %d, want %d", idx, got, idx)
+		}
+	}
+}
+
+func TestNearestConsoleAttrNearBlack(t *testing.T) {
+	if got := nearestConsoleAttr(5, 5, 5); got != 0 {
+		t.Errorf("near-black RGB should map to attr 0, got %d", got)
+	}
+}
+
+func TestAnsi256ToRGBRanges(t *testing.T) {
+	r, g, b := ansi256ToRGB(1)
+	// xterm index 1 is red, which is console attribute 4.
+	wantR, wantG, wantB := byte(consolePalette16[4][0]), byte(consolePalette16[4][1]), byte(consolePalette16[4][2])
+	if r != wantR || g != wantG || b != wantB {
+		t.Errorf("index 1 (red) should defer to the 16-color table's red entry, got (%d,%d,%d) want (%d,%d,%d)", r, g, b, wantR, wantG, wantB)
+	}
+
+	if r, g, b := ansi256ToRGB(16); r != 0 || g != 0 || b != 0 {
+		t.Errorf("index 16 (start of the 6x6x6 cube) should be black, got (%d,%d,%d)", r, g, b)
+	}
+
+	r, g, b = ansi256ToRGB(231)
+	if r != 255 || g != 255 || b != 255 {
+		t.Errorf("index 231 (end of the 6x6x6 cube) should be white, got (%d,%d,%d)", r, g, b)
+	}
+
+	r, g, b = ansi256ToRGB(232)
+	if r != g || g != b {
+		t.Errorf("grayscale ramp entries should have equal channels, got (%d,%d,%d)", r, g, b)
+	}
+}
@@ -0,0 +1,259 @@
+//go:build windows
+
+package tui
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Raw INPUT_RECORD decoding. golang.org/x/sys/windows doesn't expose
+// ReadConsoleInputW or the console input record layouts, so we bind them
+// directly against kernel32, the way containerd/console and
+// Azure/go-ansiterm do.
+
+const (
+	keyEventCode              = 0x0001
+	mouseEventCode            = 0x0002
+	windowBufferSizeEventCode = 0x0004
+	menuEventCode             = 0x0008
+	focusEventCode            = 0x0010
+)
+
+var (
+	kernel32              = windows.NewLazySystemDLL("kernel32.dll")
+	procReadConsoleInputW = kernel32.NewProc("ReadConsoleInputW")
+)
+
+// inputRecord mirrors INPUT_RECORD: a 16-byte union big enough to hold any
+// of the event records we care about, discriminated by eventType.
+type inputRecord struct {
+	eventType uint16
+	_         uint16 // alignment padding
+	event     [16]byte
+}
+
+type keyEventRecord struct {
+	keyDown         int32
+	repeatCount     uint16
+	virtualKeyCode  uint16
+	virtualScanCode uint16
+	unicodeChar     uint16
+	controlKeyState uint32
+}
+
+type mouseEventRecord struct {
+	x, y            int16
+	buttonState     uint32
+	controlKeyState uint32
+	eventFlags      uint32
+}
+
+type windowBufferSizeRecord struct {
+	x, y int16
+}
+
+type focusEventRecord struct {
+	setFocus int32
+}
+
+func readConsoleInput(handle windows.Handle, max int) ([]inputRecord, error) {
+	records := make([]inputRecord, max)
+	var read uint32
+	r1, _, err := procReadConsoleInputW.Call(
+		uintptr(handle),
+		uintptr(unsafe.Pointer(&records[0])),
+		uintptr(max),
+		uintptr(unsafe.Pointer(&read)),
+	)
+	if r1 == 0 {
+		return nil, fmt.Errorf("ReadConsoleInputW: %w", err)
+	}
+	return records[:read], nil
+}
+
+func (rec *inputRecord) asKeyEvent() keyEventRecord {
+	return *(*keyEventRecord)(unsafe.Pointer(&rec.event[0]))
+}
+
+func (rec *inputRecord) asMouseEvent() mouseEventRecord {
+	return *(*mouseEventRecord)(unsafe.Pointer(&rec.event[0]))
+}
+
+func (rec *inputRecord) asWindowBufferSizeEvent() windowBufferSizeRecord {
+	return *(*windowBufferSizeRecord)(unsafe.Pointer(&rec.event[0]))
+}
+
+func (rec *inputRecord) asFocusEvent() focusEventRecord {
+	return *(*focusEventRecord)(unsafe.Pointer(&rec.event[0]))
+}
+
+// Virtual-key codes for the navigation and function keys that don't carry
+// a printable unicodeChar.
+const (
+	vkPrior  = 0x21
+	vkNext   = 0x22
+	vkEnd    = 0x23
+	vkHome   = 0x24
+	vkLeft   = 0x25
+	vkUp     = 0x26
+	vkRight  = 0x27
+	vkDown   = 0x28
+	vkInsert = 0x2D
+	vkDelete = 0x2E
+	vkF1     = 0x70
+	vkF12    = 0x7B
+)
+
+// navKeyVT maps the virtual-key codes above to the same CSI sequences
+// xterm sends, since fzf's key parser is already written against those.
+var navKeyVT = map[uint16]string{
+	vkUp:     "\x1b[A",
+	vkDown:   "\x1b[B",
+	vkRight:  "\x1b[C",
+	vkLeft:   "\x1b[D",
+	vkHome:   "\x1b[H",
+	vkEnd:    "\x1b[F",
+	vkInsert: "\x1b[2~",
+	vkDelete: "\x1b[3~",
+	vkPrior:  "\x1b[5~",
+	vkNext:   "\x1b[6~",
+}
+
+var fKeyVT = map[uint16]string{
+	vkF1 + 0: "\x1bOP", vkF1 + 1: "\x1bOQ", vkF1 + 2: "\x1bOR", vkF1 + 3: "\x1bOS",
+	vkF1 + 4: "\x1b[15~", vkF1 + 5: "\x1b[17~", vkF1 + 6: "\x1b[18~", vkF1 + 7: "\x1b[19~",
+	vkF1 + 8: "\x1b[20~", vkF1 + 9: "\x1b[21~", vkF1 + 10: "\x1b[23~", vkF1 + 11: "\x1b[24~",
+}
+
+// keyEventToVT synthesizes the VT byte sequence fzf's key parser expects
+// for a single KEY_EVENT_RECORD, so the ReadConsoleInputW pipeline can feed
+// higher layers exactly what the old byte-at-a-time CONIN$ reader did.
+func keyEventToVT(key keyEventRecord) []byte {
+	if key.unicodeChar != 0 {
+		return []byte(string(rune(key.unicodeChar)))
+	}
+	if seq, ok := navKeyVT[key.virtualKeyCode]; ok {
+		return []byte(seq)
+	}
+	if seq, ok := fKeyVT[key.virtualKeyCode]; ok {
+		return []byte(seq)
+	}
+	return nil
+}
+
+// waitableTimeout returns a channel that closes after ms milliseconds,
+// backed by a Windows waitable timer instead of a Go timer, matching how
+// the rest of the platform file waits on native objects (stopEvent,
+// console input handles) rather than time.After.
+func waitableTimeout(ms int64) <-chan struct{} {
+	done := make(chan struct{})
+	timer, err := windows.CreateWaitableTimer(nil, true, nil)
+	if err != nil {
+		close(done)
+		return done
+	}
+	due := windows.Filetime{}
+	setFiletimeRelative(&due, ms)
+	if err := windows.SetWaitableTimer(timer, &due, 0, 0, 0, false); err != nil {
+		windows.CloseHandle(timer)
+		close(done)
+		return done
+	}
+	go func() {
+		defer windows.CloseHandle(timer)
+		windows.WaitForSingleObject(timer, windows.INFINITE)
+		close(done)
+	}()
+	return done
+}
+
+// setFiletimeRelative encodes a relative due time (negative 100ns units,
+// per SetWaitableTimer's convention) ms milliseconds from now.
+func setFiletimeRelative(ft *windows.Filetime, ms int64) {
+	ticks := uint64(-ms * 10000)
+	ft.LowDateTime = uint32(ticks & 0xffffffff)
+	ft.HighDateTime = uint32(ticks >> 32)
+}
+
+// MOUSE_EVENT_RECORD.dwButtonState / dwEventFlags bits we care about.
+const (
+	fromLeft1stButtonPressed = 0x0001
+	rightmostButtonPressed   = 0x0002
+
+	mouseMoved    = 0x0001
+	doubleClick   = 0x0002
+	mouseWheeled  = 0x0004
+	mouseHWheeled = 0x0008
+
+	shiftPressed    = 0x0010
+	leftAltPressed  = 0x0002 | 0x0001
+	leftCtrlPressed = 0x0008 | 0x0004
+)
+
+// mouseEventToVT translates a MOUSE_EVENT_RECORD into the xterm SGR mouse
+// sequence (`ESC[<b;x;yM` / `...m`) fzf's Unix mouse parsing already
+// understands, so native Windows mouse support doesn't need a parallel
+// MouseEvent decoder. lastButtons tracks the previous button bitmask so we
+// can tell a press from a release, since the console only reports the
+// current state rather than discrete down/up events.
+func mouseEventToVT(mouse mouseEventRecord, lastButtons *uint32) []byte {
+	col, row := int(mouse.x)+1, int(mouse.y)+1
+	mods := 0
+	if mouse.controlKeyState&shiftPressed != 0 {
+		mods |= 4
+	}
+	if mouse.controlKeyState&(leftAltPressed) != 0 {
+		mods |= 8
+	}
+	if mouse.controlKeyState&(leftCtrlPressed) != 0 {
+		mods |= 16
+	}
+
+	if mouse.eventFlags&mouseWheeled != 0 {
+		btn := 64 | mods
+		if int32(mouse.buttonState) < 0 {
+			btn |= 1
+		}
+		*lastButtons = 0
+		return sgrMouse(btn, col, row, true)
+	}
+
+	pressed := mouse.buttonState & (fromLeft1stButtonPressed | rightmostButtonPressed)
+	changed := pressed ^ *lastButtons
+	defer func() { *lastButtons = pressed }()
+
+	switch {
+	case mouse.eventFlags&mouseMoved != 0 && pressed != 0:
+		btn := 32 | mods
+		if pressed&rightmostButtonPressed != 0 {
+			btn |= 2
+		}
+		return sgrMouse(btn, col, row, true)
+	case changed&fromLeft1stButtonPressed != 0:
+		return sgrMouse(mods, col, row, pressed&fromLeft1stButtonPressed != 0)
+	case changed&rightmostButtonPressed != 0:
+		return sgrMouse(mods|2, col, row, pressed&rightmostButtonPressed != 0)
+	}
+	return nil
+}
+
+func sgrMouse(btn, col, row int, press bool) []byte {
+	final := byte('M')
+	if !press {
+		final = 'm'
+	}
+	return []byte(fmt.Sprintf("\x1b[<%d;%d;%d%c", btn, col, row, final))
+}
+
+// focusEventToVT mirrors the xterm focus-in/focus-out escape sequences
+// (`ESC[I` / `ESC[O`) that fzf's `focus`/`focus-lost` bind events already
+// key off of on POSIX.
+func focusEventToVT(focus focusEventRecord) []byte {
+	if focus.setFocus != 0 {
+		return []byte("\x1b[I")
+	}
+	return []byte("\x1b[O")
+}
@@ -0,0 +1,38 @@
+//go:build windows
+
+package tui
+
+import "testing"
+
+func TestKeyEventToVTPrintable(t *testing.T) {
+	got := keyEventToVT(keyEventRecord{unicodeChar: 'a'})
+	if string(got) != "a" {
+		t.Errorf("printable key should pass through unchanged, got %q", got)
+	}
+}
+
+func TestKeyEventToVTNavigation(t *testing.T) {
+	cases := map[uint16]string{
+		vkUp:    "\x1b[A",
+		vkDown:  "\x1b[B",
+		vkLeft:  "\x1b[D",
+		vkRight: "\x1b[C",
+		vkHome:  "\x1b[H",
+		vkEnd:   "\x1b[F",
+	}
+	for vk, want := range cases {
+		got := keyEventToVT(keyEventRecord{virtualKeyCode: vk})
+		if string(got) != want {
+			t.Errorf("vk %#x: got %q, want %q", vk, got, want)
+		}
+	}
+}
+
+func TestKeyEventToVTUnknownKey(t *testing.T) {
+	// A bare modifier key-down (e.g. Shift) carries neither a unicodeChar
+	// nor a virtual key we translate; it should produce nothing rather
+	// than an empty escape sequence.
+	if got := keyEventToVT(keyEventRecord{virtualKeyCode: 0x10}); got != nil {
+		t.Errorf("unmapped virtual key should produce no bytes, got %q", got)
+	}
+}
@@ -0,0 +1,47 @@
+//go:build windows
+
+package tui
+
+import "testing"
+
+func TestMouseEventToVTLeftClickAndRelease(t *testing.T) {
+	var lastButtons uint32
+
+	press := mouseEventToVT(mouseEventRecord{x: 4, y: 9, buttonState: fromLeft1stButtonPressed}, &lastButtons)
+	if string(press) != "\x1b[<0;5;10M" {
+		t.Errorf("left press: got %q", press)
+	}
+	if lastButtons != fromLeft1stButtonPressed {
+		t.Errorf("lastButtons not updated after press: got %#x", lastButtons)
+	}
+
+	release := mouseEventToVT(mouseEventRecord{x: 4, y: 9, buttonState: 0}, &lastButtons)
+	if string(release) != "\x1b[<0;5;10m" {
+		t.Errorf("left release: got %q", release)
+	}
+	if lastButtons != 0 {
+		t.Errorf("lastButtons not cleared after release: got %#x", lastButtons)
+	}
+}
+
+func TestMouseEventToVTWheel(t *testing.T) {
+	var lastButtons uint32
+	// A positive high word in dwButtonState is scroll up; a negative one
+	// (scroll down) makes the whole value a negative int32.
+	up := mouseEventToVT(mouseEventRecord{x: 0, y: 0, buttonState: 0x00780000, eventFlags: mouseWheeled}, &lastButtons)
+	if string(up) != "\x1b[<64;1;1M" {
+		t.Errorf("wheel up: got %q", up)
+	}
+
+	down := mouseEventToVT(mouseEventRecord{x: 0, y: 0, buttonState: 0xff880000, eventFlags: mouseWheeled}, &lastButtons)
+	if string(down) != "\x1b[<65;1;1M" {
+		t.Errorf("wheel down: got %q", down)
+	}
+}
+
+func TestMouseEventToVTNoChange(t *testing.T) {
+	var lastButtons uint32
+	if seq := mouseEventToVT(mouseEventRecord{eventFlags: mouseMoved}, &lastButtons); seq != nil {
+		t.Errorf("plain move with no buttons pressed should emit nothing, got %q", seq)
+	}
+}
@@ -0,0 +1,93 @@
+//go:build windows
+
+package tui
+
+import "os"
+
+// TerminalProfile captures how much of xterm's feature set the host
+// terminal actually understands, so the Light renderer and the things
+// that sit above it (the preview window, spinner glyphs, the mouse
+// pipeline) can adapt instead of assuming every Windows console behaves
+// like legacy conhost.
+type TerminalProfile struct {
+	Name              string
+	SupportsTruecolor bool
+	// SupportsMouseSGR means the terminal itself already translates
+	// clicks into xterm SGR mouse sequences independently of our native
+	// ReadConsoleInputW-based mouse pipeline (currently only true for
+	// ConEmu with ANSI emulation on), so dispatchInputRecords should
+	// leave MOUSE_EVENT_RECORDs alone rather than double-report them.
+	// It is not a general mouse-support flag: every profile that leaves
+	// it false keeps native mouse translation on.
+	SupportsMouseSGR       bool
+	SupportsAltScreen      bool
+	SupportsCursorShape    bool
+	SupportsBracketedPaste bool
+}
+
+// legacyConsoleProfile is what a bare conhost window (no VT support) can
+// do: nothing beyond what the Console API itself provides.
+var legacyConsoleProfile = TerminalProfile{Name: "conhost"}
+
+// vtConsoleProfile is the default for a console that passed the VT probe
+// but that we can't otherwise identify.
+var vtConsoleProfile = TerminalProfile{
+	Name:                   "vt",
+	SupportsAltScreen:      true,
+	SupportsCursorShape:    true,
+	SupportsBracketedPaste: true,
+}
+
+// detectWindowsTerminal identifies the host terminal emulator from its
+// well-known environment variables and returns the matching capability
+// profile. When nothing matches it falls back to IsLightRendererSupported,
+// whose VT probe runs against stderr; CONOUT$ itself is not probed.
+func detectWindowsTerminal() TerminalProfile {
+	switch {
+	case os.Getenv("WT_SESSION") != "":
+		return TerminalProfile{
+			Name: "Windows Terminal", SupportsTruecolor: true,
+			SupportsAltScreen: true, SupportsCursorShape: true, SupportsBracketedPaste: true,
+		}
+	case os.Getenv("WEZTERM_EXECUTABLE") != "":
+		return TerminalProfile{
+			Name: "WezTerm", SupportsTruecolor: true,
+			SupportsAltScreen: true, SupportsCursorShape: true, SupportsBracketedPaste: true,
+		}
+	case os.Getenv("ALACRITTY_LOG") != "":
+		return TerminalProfile{
+			Name: "Alacritty", SupportsTruecolor: true,
+			SupportsAltScreen: true, SupportsCursorShape: true, SupportsBracketedPaste: true,
+		}
+	case os.Getenv("ConEmuPID") != "":
+		profile := TerminalProfile{
+			Name: "ConEmu", SupportsTruecolor: true, SupportsAltScreen: true,
+			SupportsCursorShape: true, SupportsBracketedPaste: true,
+		}
+		// With ANSI emulation on, ConEmu already translates mouse clicks
+		// into SGR sequences itself; our native mouse pipeline would
+		// otherwise double-report them.
+		profile.SupportsMouseSGR = os.Getenv("ConEmuANSI") == "ON"
+		return profile
+	case os.Getenv("MSYSTEM") != "":
+		return TerminalProfile{
+			Name: "MSYS2/mingw", SupportsAltScreen: true, SupportsBracketedPaste: true,
+		}
+	case os.Getenv("TERM_PROGRAM") != "":
+		return TerminalProfile{
+			Name: os.Getenv("TERM_PROGRAM"), SupportsTruecolor: true,
+			SupportsAltScreen: true, SupportsCursorShape: true, SupportsBracketedPaste: true,
+		}
+	case IsLightRendererSupported():
+		return vtConsoleProfile
+	default:
+		return legacyConsoleProfile
+	}
+}
+
+// TerminalProfile returns the capability profile detected for the current
+// host terminal, so callers outside the renderer (preview window sizing,
+// spinner glyph choice) can adapt without re-implementing detection.
+func (r *LightRenderer) TerminalProfile() TerminalProfile {
+	return r.termProfile
+}
@@ -0,0 +1,61 @@
+//go:build windows
+
+package tui
+
+import (
+	"os"
+	"testing"
+)
+
+// clearTerminalEnv removes every env var detectWindowsTerminal inspects,
+// so each test starts from a clean slate regardless of the host running it.
+func clearTerminalEnv(t *testing.T) {
+	t.Helper()
+	for _, k := range []string{"WT_SESSION", "WEZTERM_EXECUTABLE", "ALACRITTY_LOG", "ConEmuPID", "ConEmuANSI", "MSYSTEM", "TERM_PROGRAM"} {
+		old, had := os.LookupEnv(k)
+		os.Unsetenv(k)
+		t.Cleanup(func() {
+			if had {
+				os.Setenv(k, old)
+			}
+		})
+	}
+}
+
+func TestDetectWindowsTerminalWindowsTerminal(t *testing.T) {
+	clearTerminalEnv(t)
+	os.Setenv("WT_SESSION", "1")
+	profile := detectWindowsTerminal()
+	if profile.Name != "Windows Terminal" || !profile.SupportsTruecolor {
+		t.Errorf("got %+v", profile)
+	}
+	// Windows Terminal sends its own mouse clicks straight through
+	// ReadConsoleInputW as MOUSE_EVENT_RECORDs; there's no second,
+	// terminal-emitted SGR stream for our native pipeline to collide
+	// with, so this must stay false here.
+	if profile.SupportsMouseSGR {
+		t.Errorf("Windows Terminal should not disable native mouse translation: %+v", profile)
+	}
+}
+
+func TestDetectWindowsTerminalConEmuMouseDependsOnANSI(t *testing.T) {
+	clearTerminalEnv(t)
+	os.Setenv("ConEmuPID", "1234")
+	if profile := detectWindowsTerminal(); profile.SupportsMouseSGR {
+		t.Errorf("ConEmu without ConEmuANSI=ON should not claim native SGR mouse support: %+v", profile)
+	}
+
+	os.Setenv("ConEmuANSI", "ON")
+	if profile := detectWindowsTerminal(); !profile.SupportsMouseSGR {
+		t.Errorf("ConEmu with ConEmuANSI=ON should claim native SGR mouse support: %+v", profile)
+	}
+}
+
+func TestDetectWindowsTerminalPrecedence(t *testing.T) {
+	clearTerminalEnv(t)
+	os.Setenv("WT_SESSION", "1")
+	os.Setenv("MSYSTEM", "MINGW64")
+	if profile := detectWindowsTerminal(); profile.Name != "Windows Terminal" {
+		t.Errorf("WT_SESSION should take priority over MSYSTEM, got %+v", profile)
+	}
+}